@@ -0,0 +1,25 @@
+package analysis
+
+import "regexp"
+
+// cssURLPattern matches CSS url(...) references, with or without quotes.
+// Running it over the whole body (rather than isolating <style> blocks
+// first) picks up both standalone .css responses and <style> blocks
+// embedded in HTML for free.
+var cssURLPattern = regexp.MustCompile(`url\(\s*['"]?([^'")]+)['"]?\s*\)`)
+
+func init() {
+	Register(cssExtractor{})
+}
+
+// cssExtractor finds background images, @font-face sources, and other
+// url(...) references in CSS.
+type cssExtractor struct{}
+
+func (cssExtractor) Extract(body []byte, contentType string) []Outlink {
+	var outlinks []Outlink
+	for _, m := range cssURLPattern.FindAllSubmatch(body, -1) {
+		outlinks = append(outlinks, Outlink{URL: string(m[1]), Type: LinkTypeAsset})
+	}
+	return outlinks
+}
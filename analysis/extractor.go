@@ -0,0 +1,70 @@
+// Package analysis discovers outbound links in a fetched page so the
+// crawler knows what to visit next. Extraction is pluggable: each
+// LinkExtractor looks at the raw body (and its Content-Type) in its own
+// way -- anchor tags, asset attributes, CSS url(...) references, sitemap
+// XML -- and every registered extractor runs over every fetched resource.
+package analysis
+
+// LinkType tags why an Outlink was found, so the caller can decide whether
+// it's in scope to fetch.
+type LinkType int
+
+const (
+	// LinkTypeHref is an <a href> the crawler should follow as a page.
+	LinkTypeHref LinkType = iota
+	// LinkTypeAsset is a page dependency (image, stylesheet, script) that
+	// is only fetched when the caller opts in, e.g. via -include-assets.
+	LinkTypeAsset
+	// LinkTypeSitemap is a <loc> found in a sitemap.xml or sitemapindex
+	// document, or seeded from a robots.txt Sitemap: directive.
+	LinkTypeSitemap
+)
+
+func (t LinkType) String() string {
+	switch t {
+	case LinkTypeHref:
+		return "href"
+	case LinkTypeAsset:
+		return "asset"
+	case LinkTypeSitemap:
+		return "sitemap"
+	default:
+		return "unknown"
+	}
+}
+
+// Outlink is a URL discovered while analysing a page. URL is exactly as
+// found in the source (possibly relative); the caller resolves and
+// validates it.
+type Outlink struct {
+	URL  string
+	Type LinkType
+}
+
+// LinkExtractor pulls outlinks out of a fetched resource. contentType is the
+// response's Content-Type with any parameters stripped, so an extractor can
+// decide whether it applies (e.g. the CSS extractor only has to run on
+// text/css, though it's harmless to run on HTML too since it also needs to
+// catch <style> blocks).
+type LinkExtractor interface {
+	Extract(body []byte, contentType string) []Outlink
+}
+
+var extractors []LinkExtractor
+
+// Register adds e to the set of extractors ExtractAll runs. Extractors
+// register themselves from an init() in this package; it is not meant to be
+// called from outside analysis.
+func Register(e LinkExtractor) {
+	extractors = append(extractors, e)
+}
+
+// ExtractAll runs every registered extractor over body and concatenates
+// their outlinks.
+func ExtractAll(body []byte, contentType string) []Outlink {
+	var all []Outlink
+	for _, e := range extractors {
+		all = append(all, e.Extract(body, contentType)...)
+	}
+	return all
+}
@@ -0,0 +1,64 @@
+package analysis
+
+import (
+	"bytes"
+
+	"golang.org/x/net/html"
+)
+
+func init() {
+	Register(hrefExtractor{})
+	Register(assetExtractor{})
+}
+
+// hrefExtractor is the original <a href> extraction this package replaced
+// main.go's getUrls with.
+type hrefExtractor struct{}
+
+func (hrefExtractor) Extract(body []byte, contentType string) []Outlink {
+	return extractAttrs(body, map[string]string{"a": "href"}, LinkTypeHref)
+}
+
+// assetExtractor finds page dependencies referenced via <link>, <script>,
+// <img>, and <source>, so a crawl with -include-assets can mirror a
+// browsable copy of a site rather than just its HTML.
+type assetExtractor struct{}
+
+func (assetExtractor) Extract(body []byte, contentType string) []Outlink {
+	return extractAttrs(body, map[string]string{
+		"link":   "href",
+		"script": "src",
+		"img":    "src",
+		"source": "src",
+	}, LinkTypeAsset)
+}
+
+// extractAttrs walks the HTML token stream and, for every tag with a rule in
+// tagAttrs, emits the configured attribute's value as an Outlink of typ.
+func extractAttrs(body []byte, tagAttrs map[string]string, typ LinkType) []Outlink {
+	var outlinks []Outlink
+
+	tokenizer := html.NewTokenizer(bytes.NewReader(body))
+	for {
+		tt := tokenizer.Next()
+		if tt == html.ErrorToken {
+			break
+		}
+		if tt != html.StartTagToken && tt != html.SelfClosingTagToken {
+			continue
+		}
+
+		t := tokenizer.Token()
+		attrName, ok := tagAttrs[t.Data]
+		if !ok {
+			continue
+		}
+
+		for _, a := range t.Attr {
+			if a.Key == attrName && a.Val != "" {
+				outlinks = append(outlinks, Outlink{URL: a.Val, Type: typ})
+			}
+		}
+	}
+	return outlinks
+}
@@ -0,0 +1,30 @@
+package analysis
+
+import "testing"
+
+func TestExtractAttrsHref(t *testing.T) {
+	body := []byte(`<html><body><a href="/a">a</a><a href="">empty</a><a>missing</a></body></html>`)
+	got := extractAttrs(body, map[string]string{"a": "href"}, LinkTypeHref)
+	if len(got) != 1 || got[0].URL != "/a" || got[0].Type != LinkTypeHref {
+		t.Fatalf("extractAttrs = %v, want single /a href outlink", got)
+	}
+}
+
+func TestExtractAttrsAssets(t *testing.T) {
+	body := []byte(`<html><head><link href="/style.css"><script src="/app.js"></script></head>` +
+		`<body><img src="/logo.png"><source src="/clip.mp4"></body></html>`)
+	got := assetExtractor{}.Extract(body, "text/html")
+
+	want := map[string]bool{"/style.css": true, "/app.js": true, "/logo.png": true, "/clip.mp4": true}
+	if len(got) != len(want) {
+		t.Fatalf("assetExtractor.Extract = %v, want %d outlinks", got, len(want))
+	}
+	for _, ol := range got {
+		if ol.Type != LinkTypeAsset {
+			t.Fatalf("outlink %v has Type=%s, want asset", ol, ol.Type)
+		}
+		if !want[ol.URL] {
+			t.Fatalf("unexpected outlink url=%s", ol.URL)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package analysis
+
+import "encoding/xml"
+
+type sitemapURLSet struct {
+	XMLName xml.Name `xml:"urlset"`
+	URLs    []struct {
+		Loc string `xml:"loc"`
+	} `xml:"url"`
+}
+
+type sitemapIndex struct {
+	XMLName  xml.Name `xml:"sitemapindex"`
+	Sitemaps []struct {
+		Loc string `xml:"loc"`
+	} `xml:"sitemap"`
+}
+
+func init() {
+	Register(sitemapExtractor{})
+}
+
+// sitemapExtractor parses sitemap.xml (a urlset) and sitemapindex
+// documents. It is safe to run against every fetched body: non-XML content
+// simply fails to unmarshal and yields no outlinks.
+type sitemapExtractor struct{}
+
+func (sitemapExtractor) Extract(body []byte, contentType string) []Outlink {
+	var urlset sitemapURLSet
+	if err := xml.Unmarshal(body, &urlset); err == nil && len(urlset.URLs) > 0 {
+		outlinks := make([]Outlink, 0, len(urlset.URLs))
+		for _, u := range urlset.URLs {
+			outlinks = append(outlinks, Outlink{URL: u.Loc, Type: LinkTypeSitemap})
+		}
+		return outlinks
+	}
+
+	var index sitemapIndex
+	if err := xml.Unmarshal(body, &index); err == nil && len(index.Sitemaps) > 0 {
+		outlinks := make([]Outlink, 0, len(index.Sitemaps))
+		for _, s := range index.Sitemaps {
+			outlinks = append(outlinks, Outlink{URL: s.Loc, Type: LinkTypeSitemap})
+		}
+		return outlinks
+	}
+
+	return nil
+}
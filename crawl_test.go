@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dibusan/selective-site-crawler/analysis"
+)
+
+// TestCrawlDrainsFrontier checks the worker pool this test belongs to:
+// every seed and every url it discovers gets processed exactly once, and
+// done closes once the frontier is empty.
+func TestCrawlDrainsFrontier(t *testing.T) {
+	urlSet = newConcurrentStorage("example.com", nil)
+	defer func() { urlSet = nil }()
+
+	origScrape := scrapeFunc
+	defer func() { scrapeFunc = origScrape }()
+
+	var visited int32
+	scrapeFunc = func(ctx context.Context, u url.URL, typ analysis.LinkType) ([]resolvedLink, error) {
+		atomic.AddInt32(&visited, 1)
+		if u.Path == "/a" {
+			return []resolvedLink{{URL: url.URL{Scheme: "https", Host: "example.com", Path: "/b"}}}, nil
+		}
+		return nil, nil
+	}
+
+	seeds := []frontierItem{{URL: url.URL{Scheme: "https", Host: "example.com", Path: "/a"}, Depth: 0}}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	done := crawl(ctx, urlSet, 2, -1, seeds)
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("crawl did not drain the frontier in time")
+	}
+
+	if got := atomic.LoadInt32(&visited); got != 2 {
+		t.Fatalf("visited = %d, want 2 (seed + discovered url)", got)
+	}
+}
+
+// TestCrawlStopsOnCancel checks that cancelling ctx drains the frontier
+// (closes done) even while urls remain unprocessed, instead of hanging.
+func TestCrawlStopsOnCancel(t *testing.T) {
+	urlSet = newConcurrentStorage("example.com", nil)
+	defer func() { urlSet = nil }()
+
+	origScrape := scrapeFunc
+	defer func() { scrapeFunc = origScrape }()
+
+	block := make(chan struct{})
+	scrapeFunc = func(ctx context.Context, u url.URL, typ analysis.LinkType) ([]resolvedLink, error) {
+		<-block
+		return nil, nil
+	}
+
+	seeds := []frontierItem{{URL: url.URL{Scheme: "https", Host: "example.com", Path: "/a"}, Depth: 0}}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := crawl(ctx, urlSet, 1, -1, seeds)
+
+	cancel()
+	close(block)
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("crawl did not close done after ctx cancellation")
+	}
+}
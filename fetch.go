@@ -0,0 +1,70 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// httpClient is shared by every fetch so a single -request-timeout and
+// connection pool apply across the whole crawl, instead of each request
+// hanging indefinitely as plain http.Get does.
+var httpClient = &http.Client{}
+
+// allowedContentTypes is the -content-types allowlist, populated at
+// startup. An empty list means "fetch everything".
+var allowedContentTypes []string
+
+func initHttpClient(requestTimeoutSeconds int) {
+	httpClient = &http.Client{Timeout: time.Duration(requestTimeoutSeconds) * time.Second}
+}
+
+// mediaType strips any parameters (e.g. "; charset=utf-8") off a raw
+// Content-Type header value.
+func mediaType(ct string) string {
+	return strings.TrimSpace(strings.SplitN(ct, ";", 2)[0])
+}
+
+// contentTypeAllowed reports whether ct (the raw Content-Type header value,
+// parameters and all) matches one of allowedContentTypes.
+func contentTypeAllowed(ct string) bool {
+	if len(allowedContentTypes) == 0 {
+		return true
+	}
+
+	mt := mediaType(ct)
+	for _, allowed := range allowedContentTypes {
+		if strings.EqualFold(mt, allowed) {
+			return true
+		}
+	}
+	return false
+}
+
+// preflight issues a HEAD request for u and reports whether its Content-Type
+// is one the crawler is configured to fetch, so a GET is never spent on an
+// ISO, video, or other large binary an <a href> happens to point at.
+//
+// If the server omits Content-Type, preflight reports true. If the HEAD
+// request itself fails, preflight returns false with the error; scrape then
+// fetches the page anyway rather than silently dropping it.
+func preflight(u url.URL) (bool, error) {
+	req, err := http.NewRequest(http.MethodHead, u.String(), nil)
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("User-Agent", userAgent)
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return false, err
+	}
+	resp.Body.Close()
+
+	ct := resp.Header.Get("Content-Type")
+	if ct == "" {
+		return true, nil
+	}
+	return contentTypeAllowed(ct), nil
+}
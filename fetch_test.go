@@ -0,0 +1,110 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestMediaType(t *testing.T) {
+	cases := map[string]string{
+		"text/html; charset=utf-8": "text/html",
+		"text/html":                "text/html",
+		" application/json ":       "application/json",
+		"":                         "",
+	}
+	for in, want := range cases {
+		if got := mediaType(in); got != want {
+			t.Errorf("mediaType(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestContentTypeAllowed(t *testing.T) {
+	prev := allowedContentTypes
+	defer func() { allowedContentTypes = prev }()
+
+	allowedContentTypes = []string{"text/html", "application/xml"}
+
+	if !contentTypeAllowed("text/html; charset=utf-8") {
+		t.Error("expected text/html; charset=utf-8 to be allowed")
+	}
+	if !contentTypeAllowed("Application/XML") {
+		t.Error("expected a case-insensitive match against application/xml")
+	}
+	if contentTypeAllowed("image/png") {
+		t.Error("expected image/png to be rejected")
+	}
+}
+
+func TestContentTypeAllowedEmptyAllowlistAllowsEverything(t *testing.T) {
+	prev := allowedContentTypes
+	allowedContentTypes = nil
+	defer func() { allowedContentTypes = prev }()
+
+	if !contentTypeAllowed("application/octet-stream") {
+		t.Error("empty -content-types allowlist should allow everything")
+	}
+}
+
+func TestPreflightUsesContentTypeAllowlist(t *testing.T) {
+	prevClient := httpClient
+	prevAllowed := allowedContentTypes
+	defer func() { httpClient = prevClient; allowedContentTypes = prevAllowed }()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/image" {
+			w.Header().Set("Content-Type", "image/png")
+		} else {
+			w.Header().Set("Content-Type", "text/html")
+		}
+	}))
+	defer srv.Close()
+
+	httpClient = srv.Client()
+	allowedContentTypes = []string{"text/html"}
+
+	srvURL, _ := url.Parse(srv.URL)
+
+	pageURL := *srvURL
+	pageURL.Path = "/page"
+	allowed, err := preflight(pageURL)
+	if err != nil {
+		t.Fatalf("preflight(/page): %s", err)
+	}
+	if !allowed {
+		t.Error("preflight(/page) = false, want true (text/html is allowed)")
+	}
+
+	imageURL := *srvURL
+	imageURL.Path = "/image"
+	allowed, err = preflight(imageURL)
+	if err != nil {
+		t.Fatalf("preflight(/image): %s", err)
+	}
+	if allowed {
+		t.Error("preflight(/image) = true, want false (image/png is not in the allowlist)")
+	}
+}
+
+func TestPreflightMissingContentTypeFetchesAnyway(t *testing.T) {
+	prevClient := httpClient
+	prevAllowed := allowedContentTypes
+	defer func() { httpClient = prevClient; allowedContentTypes = prevAllowed }()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	httpClient = srv.Client()
+	allowedContentTypes = []string{"text/html"}
+
+	srvURL, _ := url.Parse(srv.URL)
+	allowed, err := preflight(*srvURL)
+	if err != nil {
+		t.Fatalf("preflight: %s", err)
+	}
+	if !allowed {
+		t.Error("preflight with no Content-Type header = false, want true (errs toward fetching)")
+	}
+}
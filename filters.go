@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// stringList accumulates repeated occurrences of a flag into a slice, since
+// the stdlib flag package only supports single-value flags out of the box.
+// Used for -exclude, -include, and -exclude-from-file, each of which may be
+// passed more than once.
+type stringList []string
+
+func (s *stringList) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *stringList) Set(v string) error {
+	*s = append(*s, v)
+	return nil
+}
+
+var (
+	excludePatterns []*regexp.Regexp
+	includePatterns []*regexp.Regexp
+)
+
+// compileFilters turns the raw -exclude/-include/-exclude-from-file flag
+// values into the regexps shouldFetch consults.
+func compileFilters(excludeRaw stringList, includeRaw stringList, excludeFromFile stringList) error {
+	for _, path := range excludeFromFile {
+		patterns, err := readPatternFile(path)
+		if err != nil {
+			return fmt.Errorf("could not read -exclude-from-file=%s: %s", path, err)
+		}
+		excludeRaw = append(excludeRaw, patterns...)
+	}
+
+	for _, p := range excludeRaw {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return fmt.Errorf("invalid -exclude pattern %q: %s", p, err)
+		}
+		excludePatterns = append(excludePatterns, re)
+	}
+
+	for _, p := range includeRaw {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return fmt.Errorf("invalid -include pattern %q: %s", p, err)
+		}
+		includePatterns = append(includePatterns, re)
+	}
+
+	return nil
+}
+
+// readPatternFile reads one regex per line, ignoring blank lines and lines
+// starting with '#'.
+func readPatternFile(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var patterns []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		patterns = append(patterns, line)
+	}
+	return patterns, scanner.Err()
+}
+
+// shouldFetch reports whether u passes the -exclude/-include filters. A url
+// matching any -exclude pattern is rejected unless it also matches an
+// -include pattern, which lets a user carve an exception out of a broad
+// exclude rule (e.g. exclude "/calendar/.*" but include "/calendar/index").
+func shouldFetch(u url.URL) bool {
+	s := u.String()
+
+	excluded := false
+	for _, re := range excludePatterns {
+		if re.MatchString(s) {
+			excluded = true
+			break
+		}
+	}
+	if !excluded {
+		return true
+	}
+
+	for _, re := range includePatterns {
+		if re.MatchString(s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func resetFilterState() {
+	excludePatterns = nil
+	includePatterns = nil
+}
+
+func TestShouldFetchExcludePrecedence(t *testing.T) {
+	resetFilterState()
+	defer resetFilterState()
+
+	if err := compileFilters(
+		stringList{`/calendar/.*`},
+		stringList{`/calendar/index`},
+		nil,
+	); err != nil {
+		t.Fatalf("compileFilters: %s", err)
+	}
+
+	cases := []struct {
+		path string
+		want bool
+	}{
+		{"https://example.com/about", true},
+		{"https://example.com/calendar/2026-01-01", false},
+		{"https://example.com/calendar/index", true},
+	}
+	for _, c := range cases {
+		u, _ := url.Parse(c.path)
+		if got := shouldFetch(*u); got != c.want {
+			t.Errorf("shouldFetch(%s) = %v, want %v", c.path, got, c.want)
+		}
+	}
+}
+
+func TestCompileFiltersInvalidRegex(t *testing.T) {
+	resetFilterState()
+	defer resetFilterState()
+
+	if err := compileFilters(stringList{"("}, nil, nil); err == nil {
+		t.Fatal("compileFilters with invalid -exclude regex: want error, got nil")
+	}
+}
+
+func TestCompileFiltersFromFile(t *testing.T) {
+	resetFilterState()
+	defer resetFilterState()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "excludes.txt")
+	contents := "# comment\n\n/admin/.*\n"
+	if err := os.WriteFile(path, []byte(contents), 0666); err != nil {
+		t.Fatalf("WriteFile: %s", err)
+	}
+
+	if err := compileFilters(nil, nil, stringList{path}); err != nil {
+		t.Fatalf("compileFilters: %s", err)
+	}
+
+	u, _ := url.Parse("https://example.com/admin/users")
+	if shouldFetch(*u) {
+		t.Fatal("shouldFetch(/admin/users) = true, want false (excluded via -exclude-from-file)")
+	}
+}
@@ -4,9 +4,11 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"os"
+	"os/signal"
 	"log"
 	"encoding/hex"
 	"crypto/rand"
@@ -17,26 +19,78 @@ import (
 	"io/ioutil"
 	"io"
 	"net/http"
-	"bytes"
-	"golang.org/x/net/html"
 	"time"
 	"strconv"
+
+	"github.com/dibusan/selective-site-crawler/analysis"
+)
+
+// global handles for the output subsystems introduced alongside the
+// -state/-resume/-output-max-size flags.
+var (
+	warc *warcWriter
+	state *crawlState
+	urlSet *concurrentStorage
+	payloads *payloadStore
+	shutdown context.CancelFunc
 )
 
+// frontierItem is what travels through the crawl queue: a URL plus how many
+// hops it is from the seed, so a -depth limit can be enforced without
+// threading extra state through concurrentStorage. Type records why the URL
+// was discovered (the zero value, analysis.LinkTypeHref, covers -host
+// itself and ordinary page links) so scrape can tell an asset or sitemap
+// seed apart from a page and gate it accordingly.
+type frontierItem struct {
+	URL   url.URL
+	Depth int
+	Type  analysis.LinkType
+}
+
 // concurrentStorage acts as a set. A common storage point for multiple go routines and
 // as a validator, to avoid processing urls that have already been processed by other routines.
+//
+// When a crawlState is attached, every URL added here is also persisted to
+// disk so a crawl can survive a restart (see -state/-resume). It also holds
+// the per-host robots.txt rules and rate limiters, shared across every
+// goroutine crawling that host.
 type concurrentStorage struct {
 	sync.Mutex
 	domain string
 	urls map[url.URL]bool
 	urlsSize int
+	state *crawlState
+	hosts map[string]*hostState
 }
 
-func newConcurrentStorage(d string) *concurrentStorage{
+func newConcurrentStorage(d string, state *crawlState) *concurrentStorage{
 	return &concurrentStorage{
 		domain: d,
 		urls: map[url.URL]bool{},
+		state: state,
+		hosts: map[string]*hostState{},
+	}
+}
+
+// hostStateFor returns the shared hostState for host, fetching and parsing
+// its robots.txt the first time the host is seen.
+func (c *concurrentStorage) hostStateFor(host string) *hostState {
+	c.Lock()
+	if hs, ok := c.hosts[host]; ok {
+		c.Unlock()
+		return hs
+	}
+	c.Unlock()
+
+	hs := newHostState(host)
+
+	c.Lock()
+	defer c.Unlock()
+	if existing, ok := c.hosts[host]; ok {
+		return existing
 	}
+	c.hosts[host] = hs
+	return hs
 }
 
 // Return true if the URL is unseen and was saved.
@@ -45,6 +99,10 @@ func newConcurrentStorage(d string) *concurrentStorage{
 // cannot save it, then returns an empty URL and false to let the caller
 // know not to process it.
 func (c *concurrentStorage) add(u url.URL) (bool) {
+	if !shouldFetch(u) {
+		return false
+	}
+
 	c.Lock()
 	defer c.Unlock()
 	if _, ok := c.urls[u]; ok{
@@ -52,9 +110,27 @@ func (c *concurrentStorage) add(u url.URL) (bool) {
 	}
 	c.urls[u] = true
 	c.urlsSize++
+
+	if c.state != nil {
+		if _, err := c.state.markSeen(u); err != nil {
+			logWarning("could not persist url=" + u.String() + " to state db. err=" + err.Error())
+		}
+	}
 	return true
 }
 
+// preload marks urls as already known without re-persisting them to the
+// state db (they're already there). Used on -resume to seed the in-memory
+// dedupe set with every url a prior run already fetched, so rediscovering
+// one (e.g. via a shared nav link) doesn't trigger a redundant re-fetch.
+func (c *concurrentStorage) preload(urls []url.URL) {
+	c.Lock()
+	defer c.Unlock()
+	for _, u := range urls {
+		c.urls[u] = true
+	}
+}
+
 func (c *concurrentStorage) size() int {
 	c.Lock()
 	defer c.Unlock()
@@ -140,6 +216,23 @@ var (
 	timeout int
 	pageLimit int
 	pageCounter int
+	outputMaxSizeMB int
+	stateDir string
+	resume bool
+	concurrency int
+	maxDepth int
+	maxBodyBytes int64
+	contentTypes string
+	requestTimeout int
+	delaySeconds float64
+	throttle float64
+	includeAssets bool
+	excludeRelated bool
+	excludeFlags stringList
+	includeFlags stringList
+	excludeFromFileFlags stringList
+	payloadDir string
+	shardLevels int
 )
 
 
@@ -155,30 +248,34 @@ func validateUrl(u url.URL) error {
 
 // Get the contents of a web page
 // Return error if the request fails
-func getHttp(url url.URL) (io.ReadCloser, error) {
-	resp, err := http.Get(url.String())
+func getHttp(url url.URL) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodGet, url.String(), nil)
 	if err != nil {
-		log.Printf("HTTP failed to GET url=%s. error=%s\n", url.String(), err)
 		return nil, err
 	}
+	req.Header.Set("User-Agent", userAgent)
 
-	return resp.Body, nil
-}
-
-// Extract the href attribute from a Token
-func getHref(t html.Token) (ok bool, href string) {
-	for _, a := range t.Attr {
-		if a.Key == "href" {
-			href = a.Val
-			ok = true
-		}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		log.Printf("HTTP failed to GET url=%s. error=%s\n", url.String(), err)
+		return nil, err
 	}
-	return
+
+	return resp, nil
 }
 
 // adds missing pieces to a URL and then validates it.
 // if is an invalid/non-accessible URL then return false
-func sanitizeUrl(href string, domain string) (url.URL, bool){
+//
+// pageHost fills in the host of a relative href (the page it was found on);
+// enforceHost, when set, additionally rejects any url -- relative or
+// absolute -- whose resolved host isn't scopeHost, so off-domain hrefs
+// picked up while following an -exclude-related=false asset chain aren't
+// mistaken for in-scope pages just because they resolved against the
+// foreign page they were found on. Extractors for off-domain dependencies
+// (e.g. CDN-hosted assets) pass enforceHost=false so those dependencies can
+// still be fetched when -exclude-related is off.
+func sanitizeUrl(href string, pageHost string, enforceHost bool, scopeHost string) (url.URL, bool){
 	if strings.Trim(href, " ") == ""{
 		return url.URL{}, false
 	}
@@ -190,8 +287,12 @@ func sanitizeUrl(href string, domain string) (url.URL, bool){
 	}
 
 	if u.Host == ""{
-		u.Host = domain
-	} else if u.Host != domain || u.Path == "/" || u.Path == ""{
+		u.Host = pageHost
+	} else if u.Path == "/" || u.Path == "" {
+		return url.URL{}, false
+	}
+
+	if enforceHost && u.Host != scopeHost {
 		return url.URL{}, false
 	}
 
@@ -209,129 +310,221 @@ func sanitizeUrl(href string, domain string) (url.URL, bool){
 	return *u, true
 }
 
-// Get only urls of the specified domain given the body of a web page
-func getUrls(body []byte, domain string) ([]url.URL, error) {
-
-	// holds only valid urls
-	var urls []url.URL
-
-	reader := bytes.NewReader(body)
-	tokenizer := html.NewTokenizer(reader)
-
-	infinitefor:for {
-		tokenType := tokenizer.Next()
-
-		switch {
-		case tokenType == html.ErrorToken:
-			// End of the document, we're done
-			break infinitefor
-
-		case tokenType == html.StartTagToken:
-			t := tokenizer.Token()
-
-			// Check if the token is an <a> tag
-			isAnchor := t.Data == "a"
-			if !isAnchor {
-				continue
-			}
+// resolvedLink is an Outlink once its href has been resolved and validated
+// against domain. Type is carried all the way into the frontier so scrape
+// can tell an asset or sitemap url apart from an ordinary page link.
+type resolvedLink struct {
+	URL  url.URL
+	Type analysis.LinkType
+}
 
-			// Extract the href value, if there is one
-			ok, href := getHref(t)
-			if !ok {
-				continue
-			}
+// extractOutlinks runs every registered analysis.LinkExtractor over body and
+// resolves the results found on pageHost, applying -include-assets and
+// -exclude-related to decide which outlink types are followed. scopeHost is
+// the originally configured -host: it's what "in scope" is checked against,
+// which is not necessarily pageHost once an -exclude-related=false asset
+// chain has wandered onto a related host such as a CDN.
+func extractOutlinks(body []byte, contentType string, pageHost string, scopeHost string) []resolvedLink {
+	var links []resolvedLink
+
+	for _, ol := range analysis.ExtractAll(body, contentType) {
+		if ol.Type == analysis.LinkTypeAsset && !includeAssets {
+			continue
+		}
 
-			if url, ok := sanitizeUrl(href, domain); ok {
-				urls = append(urls, url)
-			}
+		enforceHost := ol.Type != analysis.LinkTypeAsset || excludeRelated
+		if u, ok := sanitizeUrl(ol.URL, pageHost, enforceHost, scopeHost); ok {
+			links = append(links, resolvedLink{URL: u, Type: ol.Type})
 		}
 	}
-	return urls, nil
+	return links
 }
 
-// Save the page contents (converted to a byte array) to a file in local storage
+// Save the page contents as a WARC response record, and into the
+// content-addressed payload store/manifest.tsv.
 // Returns whether the page was saved successfully
-func savePage(url url.URL, body []byte) bool{
-	// TODO: Take save location as a CMD line flag
-	rootDir := "/tmp/scraper"
-
-	dirPath := rootDir + "/" + url.Host + url.Path
-
-	err := os.MkdirAll(dirPath, 0777)
-	if err != nil {
-		log.Printf("Cannot create directory %s. \nError: %s", dirPath, err)
+func savePage(url url.URL, statusLine string, headerBlock string, contentType string, status int, body []byte) bool{
+	if err := warc.writeResponse(url, statusLine, headerBlock, body); err != nil {
+		log.Printf("Cannot write warc record for url=%s. \nError: %s", url.String(), err)
 		return false
 	}
 
-	filePath := dirPath + "/index.html"
-
-	err = ioutil.WriteFile(filePath, body, 0777)
-	if err != nil {
-		log.Printf("Cannot write to file=%s. \nError: %s", filePath, err)
+	if err := payloads.save(url, contentType, status, body); err != nil {
+		log.Printf("Cannot save payload for url=%s. \nError: %s", url.String(), err)
 		return false
 	}
+
 	return true
 }
 
+// scrapeFunc is the indirection crawl() calls through; tests swap it out to
+// exercise the worker pool's drain/cancel handling without real HTTP.
+var scrapeFunc func(ctx context.Context, u url.URL, typ analysis.LinkType) ([]resolvedLink, error) = scrape
+
 // scrape visits a page and extracts all the valid urls for the given domain
 // Returns error if the target URL is empty, cannot be scrapped by access over HTTP,
-// urls cannot be scraped.
-func scrape(u url.URL) ([]url.URL, error) {
+// urls cannot be scraped. typ is why u is being fetched (LinkTypeHref for
+// -host itself and ordinary page links); assets and sitemap seeds carry
+// their own analysis.LinkType so the -content-types preflight gate -- aimed
+// at pages, not page dependencies -- doesn't also swallow them.
+func scrape(ctx context.Context, u url.URL, typ analysis.LinkType) ([]resolvedLink, error) {
 
 	if strings.Trim(u.String(), " ") == ""{
-		return []url.URL{}, errors.New("empty url")
+		return []resolvedLink{}, errors.New("empty url")
+	}
+
+	hs := urlSet.hostStateFor(u.Host)
+	if !hs.allowed(u.Path) {
+		logDebug("robots.txt disallows url=" + u.String() + " for user-agent=" + userAgent)
+		return []resolvedLink{}, nil
+	}
+
+	if err := hs.limiter.Wait(ctx); err != nil {
+		return []resolvedLink{}, err
+	}
+	if err := globalLimiter.Wait(ctx); err != nil {
+		return []resolvedLink{}, err
+	}
+
+	if typ == analysis.LinkTypeHref {
+		allowed, err := preflight(u)
+		if err != nil {
+			logWarning("HEAD preflight failed for url=" + u.String() + ". err=" + err.Error() + ". fetching anyway")
+		} else if !allowed {
+			logDebug("skipping url=" + u.String() + ": content-type not in -content-types allowlist")
+			return []resolvedLink{}, nil
+		}
 	}
 
-	pageReadCloser, err := getHttp(u)
-	defer pageReadCloser.Close()
+	resp, err := getHttp(u)
 	if err != nil {
-		log.Printf("failed to get pageReadCloser at u=%s. err=%s\n", u, err)
-		return []url.URL{}, nil
+		log.Printf("failed to get response at u=%s. err=%s\n", u.String(), err)
+		return []resolvedLink{}, nil
 	}
+	defer resp.Body.Close()
 
-	page, err := ioutil.ReadAll(pageReadCloser)
+	page, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxBodyBytes))
 	if err != nil {
 		log.Printf("Could not read page buffer for url=%s\n", u.String())
-		return []url.URL{}, err
+		return []resolvedLink{}, err
 	}
 
-	if savePage(u, page) {
+	statusLine := fmt.Sprintf("HTTP/1.1 %s", resp.Status)
+	var headerBlock strings.Builder
+	resp.Header.Write(&headerBlock)
+	ct := mediaType(resp.Header.Get("Content-Type"))
+
+	if savePage(u, statusLine, headerBlock.String(), ct, resp.StatusCode, page) {
 		pageCounter++
+		if state != nil {
+			if err := state.markFetched(u); err != nil {
+				logWarning("could not mark url=" + u.String() + " fetched in state db. err=" + err.Error())
+			}
+		}
 	}
 
 	if pageLimit != -1 && pageCounter >= pageLimit {
 		logInfo("Reached page download limit=" + strconv.Itoa(pageLimit))
-		os.Exit(0)
+		if shutdown != nil {
+			shutdown()
+		}
 	}
 
-	urls, err := getUrls(page, u.Host)
-	if err != nil {
-		log.Printf("failed to extract valid urls for pageReadCloser at u=%s. err=%s\n", u, err)
-		return []url.URL{}, err
+	links := extractOutlinks(page, ct, u.Host, urlSet.domain)
+
+	if seeds := hs.sitemapSeeds(); len(seeds) > 0 {
+		for _, s := range seeds {
+			if sitemapURL, ok := sanitizeUrl(s, u.Host, true, urlSet.domain); ok {
+				links = append(links, resolvedLink{URL: sitemapURL, Type: analysis.LinkTypeSitemap})
+			}
+		}
 	}
 
-	return urls, nil
+	return links, nil
 }
 
-// crawl could be called multiple times in parallel to increase productivity.
-func crawl(urlSet *concurrentStorage, ch chan url.URL){
-	for {
+// crawl drains the frontier starting from seeds: a semaphore caps it at
+// concurrency simultaneous scrapes, it honours maxDepth, and it tracks
+// outstanding work with a sync.WaitGroup so it knows exactly when the
+// frontier is empty. Every dequeued item gets its own goroutine, which waits
+// on the semaphore; the number of goroutines waiting there is not bounded.
+// Urls are deduplicated (and persisted to the state db) when enqueued.
+//
+// It stops either when the frontier drains or when ctx is cancelled (e.g. on
+// SIGINT), and the returned channel is closed in both cases so callers can
+// select on it alongside a -timeout or -pages limit.
+func crawl(ctx context.Context, urlSet *concurrentStorage, concurrency int, maxDepth int, seeds []frontierItem) <-chan struct{} {
+	ch := make(chan frontierItem, concurrency*4)
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+
+	enqueue := func(item frontierItem) {
+		if !urlSet.add(item.URL) {
+			return
+		}
+		wg.Add(1)
 		select {
-		case u := <- ch:
-			if ok := urlSet.add(u); ok {
-				log.Printf("Received url=%s", u.String())
-				urls, err := scrape(u)
-				if err != nil {
-					log.Printf("Could not scrape url=%s.\nError: %s", u.String(), err)
-					break
-				}
-
-				for _, url := range urls {
-					go 	func() {ch <- url}()
-				}
-			}
+		case ch <- item:
+		case <-ctx.Done():
+			wg.Done()
+		}
+	}
+
+	process := func(item frontierItem) {
+		defer wg.Done()
+
+		if ctx.Err() != nil {
+			return
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case sem <- struct{}{}:
+		}
+		defer func() { <-sem }()
+
+		log.Printf("Received url=%s depth=%d", item.URL.String(), item.Depth)
+		links, err := scrapeFunc(ctx, item.URL, item.Type)
+		if err != nil {
+			log.Printf("Could not scrape url=%s.\nError: %s", item.URL.String(), err)
+			return
+		}
+
+		if maxDepth != -1 && item.Depth >= maxDepth {
+			return
+		}
+
+		for _, link := range links {
+			enqueue(frontierItem{URL: link.URL, Depth: item.Depth + 1, Type: link.Type})
 		}
 	}
+
+	done := make(chan struct{})
+
+	// The dispatcher keeps handing items off to process() (which itself
+	// bails out immediately once ctx is cancelled) until ch is closed.
+	go func() {
+		for item := range ch {
+			go process(item)
+		}
+	}()
+
+	// Seeds must be enqueued (wg.Add) before the goroutine below calls
+	// wg.Wait, otherwise it could observe a zero counter and declare the
+	// frontier drained before any work was ever queued.
+	for _, seed := range seeds {
+		enqueue(seed)
+	}
+
+	// Once every enqueued item has been accounted for -- either processed
+	// or abandoned because ctx was cancelled -- the frontier is drained.
+	go func() {
+		wg.Wait()
+		close(ch)
+		close(done)
+	}()
+
+	return done
 }
 
 // todo: unittest
@@ -352,6 +545,12 @@ func validateFlags(d string, t int, p int) error{
 }
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "replay" {
+		initLogger(VERBOSE)
+		runReplay(os.Args[2:])
+		return
+	}
+
 	initLogger(VERBOSE)
 	pageCounter = 0
 
@@ -363,8 +562,73 @@ func main() {
 	flag.IntVar(&pageLimit, "pages", -1, "Limit of pages to" +
 		" visit. If not set will run until the timeout constraint is met. At " +
 		"least one constraint needs to be set.")
+	flag.IntVar(&outputMaxSizeMB, "output-max-size", 100, "Maximum size, in " +
+		"MB, of a single WARC output file before the crawler rotates to a new one.")
+	flag.StringVar(&stateDir, "state", "./state", "Directory holding the " +
+		"resumable crawl state (a BoltDB file tracking which urls have been " +
+		"seen/fetched).")
+	flag.BoolVar(&resume, "resume", false, "Reopen the state DB under " +
+		"-state and re-enqueue urls that were seen but not yet fetched, " +
+		"instead of starting a fresh crawl from -host.")
+	flag.IntVar(&concurrency, "concurrency", 4, "Maximum number of pages " +
+		"fetched at the same time.")
+	flag.IntVar(&concurrency, "c", 4, "Shorthand for -concurrency.")
+	flag.IntVar(&maxDepth, "depth", -1, "Maximum link depth to follow from " +
+		"-host. 0 only fetches the seed page. If not set the crawl is " +
+		"unbounded by depth.")
+	flag.Int64Var(&maxBodyBytes, "max-body", 1024*1024, "Maximum response " +
+		"body size, in bytes, read from a single page.")
+	flag.StringVar(&contentTypes, "content-types", "text/html", "Comma-" +
+		"separated allowlist of Content-Type values (without parameters) " +
+		"the crawler will fetch, checked with a HEAD preflight before the " +
+		"GET. Empty disables the check.")
+	flag.IntVar(&requestTimeout, "request-timeout", 30, "Timeout, in " +
+		"seconds, applied to every HTTP request.")
+	flag.StringVar(&userAgent, "user-agent", userAgent, "User-Agent sent " +
+		"with every request, and used to select the matching group in " +
+		"robots.txt.")
+	flag.Float64Var(&delaySeconds, "delay", 0, "Minimum polite delay, in " +
+		"seconds, between requests to the same host. robots.txt's own " +
+		"Crawl-delay is honored when it asks for longer.")
+	flag.Float64Var(&throttle, "throttle", 0, "Global rate limit, in " +
+		"requests/sec, applied across all hosts in addition to the per-" +
+		"host -delay. 0 means unlimited.")
+	flag.BoolVar(&includeAssets, "include-assets", false, "Also fetch page " +
+		"dependencies (images, stylesheets, scripts) discovered via " +
+		"<link>/<script>/<img>/<source> and CSS url(...) references, not " +
+		"just <a href> pages.")
+	flag.BoolVar(&excludeRelated, "exclude-related", true, "Restrict " +
+		"-include-assets to assets on -host rather than also following " +
+		"them onto related hosts (e.g. a CDN).")
+	flag.Var(&excludeFlags, "exclude", "Regex matched against the full " +
+		"url; a match is skipped. May be given multiple times.")
+	flag.Var(&includeFlags, "include", "Regex that overrides -exclude " +
+		"when it also matches. May be given multiple times.")
+	flag.Var(&excludeFromFileFlags, "exclude-from-file", "File of -exclude " +
+		"regexes, one per line; blank lines and lines starting with '#' " +
+		"are ignored. May be given multiple times.")
+	flag.StringVar(&payloadDir, "payloads", "", "Directory for the " +
+		"content-addressed payload store and manifest.tsv. Defaults to " +
+		"-state/payloads.")
+	flag.IntVar(&shardLevels, "shard-levels", 2, "Number of sharding " +
+		"directory levels used when storing payloads by content hash.")
 	flag.Parse()
 
+	if err := compileFilters(excludeFlags, includeFlags, excludeFromFileFlags); err != nil {
+		logError("Invalid url filters. Err: " + err.Error())
+		os.Exit(1)
+	}
+
+	initHttpClient(requestTimeout)
+	if contentTypes != "" {
+		allowedContentTypes = strings.Split(contentTypes, ",")
+		for i := range allowedContentTypes {
+			allowedContentTypes[i] = strings.TrimSpace(allowedContentTypes[i])
+		}
+	}
+	crawlDelay = time.Duration(delaySeconds * float64(time.Second))
+	initGlobalLimiter(throttle)
+
 	err := validateFlags(domain, timeout, pageLimit)
 	if err != nil {
 		logError("Invalid flags. Err: " + err.Error())
@@ -389,17 +653,87 @@ func main() {
 		targetURL.Scheme = "https"
 	}
 
-	urlSet := newConcurrentStorage(targetURL.Host)
+	if err := os.MkdirAll(stateDir, 0777); err != nil {
+		logError("Could not create state dir=" + stateDir + ". Err: " + err.Error())
+		os.Exit(1)
+	}
 
-	urlCh := make(chan url.URL, 2)
-	go crawl(urlSet, urlCh)
-	go crawl(urlSet, urlCh)
+	state, err = openCrawlState(stateDir)
+	if err != nil {
+		logError("Could not open state db. Err: " + err.Error())
+		os.Exit(1)
+	}
+	defer state.Close()
 
-	urlCh <- *targetURL
+	warc, err = newWarcWriter(stateDir+"/warc", outputMaxSizeMB)
+	if err != nil {
+		logError("Could not open warc writer. Err: " + err.Error())
+		os.Exit(1)
+	}
+	defer warc.Close()
 
-	if timeout != -1 {
-		time.Sleep(time.Duration(timeout) * time.Second)
+	if payloadDir == "" {
+		payloadDir = stateDir + "/payloads"
+	}
+	payloads, err = newPayloadStore(payloadDir, shardLevels)
+	if err != nil {
+		logError("Could not open payload store. Err: " + err.Error())
+		os.Exit(1)
+	}
+	defer payloads.Close()
+
+	urlSet = newConcurrentStorage(targetURL.Host, state)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	shutdown = cancel
+	defer cancel()
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, os.Interrupt)
+	go func() {
+		<-sigCh
+		logInfo("Received interrupt, shutting down gracefully")
+		cancel()
+	}()
+
+	var seeds []frontierItem
+	if resume {
+		fetched, err := state.fetched()
+		if err != nil {
+			logError("Could not read fetched urls from state db. Err: " + err.Error())
+			os.Exit(1)
+		}
+		urlSet.preload(fetched)
+
+		pending, err := state.pending()
+		if err != nil {
+			logError("Could not read pending urls from state db. Err: " + err.Error())
+			os.Exit(1)
+		}
+		logInfo("Resuming crawl with " + strconv.Itoa(len(pending)) + " pending urls (" +
+			strconv.Itoa(len(fetched)) + " already fetched and excluded from re-fetch)")
+		for _, u := range pending {
+			seeds = append(seeds, frontierItem{URL: u, Depth: 0})
+		}
 	} else {
-		time.Sleep(time.Duration(1) * time.Hour) // Max time
+		seeds = []frontierItem{{URL: *targetURL, Depth: 0}}
+	}
+
+	done := crawl(ctx, urlSet, concurrency, maxDepth, seeds)
+
+	var timeoutCh <-chan time.Time
+	if timeout != -1 {
+		timeoutCh = time.After(time.Duration(timeout) * time.Second)
+	}
+
+	select {
+	case <-done:
+		logInfo("Frontier drained, shutting down")
+	case <-timeoutCh:
+		logInfo("Timeout reached, shutting down")
+		cancel()
+		<-done
+	case <-ctx.Done():
+		<-done
 	}
 }
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// payloadStore content-addresses fetched bodies under outDir, sharded by the
+// first shardLevels byte-pairs of the SHA-256 hash, so duplicate responses
+// (repeated 404 pages, mirrored assets, boilerplate) cost disk once instead
+// of once per URL. Every fetch still gets a row in manifest.tsv mapping its
+// url back to the payload that satisfied it.
+type payloadStore struct {
+	sync.Mutex
+	outDir      string
+	shardLevels int
+	manifest    *os.File
+}
+
+func newPayloadStore(outDir string, shardLevels int) (*payloadStore, error) {
+	if err := os.MkdirAll(outDir, 0777); err != nil {
+		return nil, fmt.Errorf("cannot create payload dir %s: %s", outDir, err)
+	}
+
+	manifestPath := filepath.Join(outDir, "manifest.tsv")
+	f, err := os.OpenFile(manifestPath, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0666)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open %s: %s", manifestPath, err)
+	}
+
+	return &payloadStore{outDir: outDir, shardLevels: shardLevels, manifest: f}, nil
+}
+
+// shardedPath returns dir/<shardLevels byte-pairs>/<hash>, e.g. with 2
+// levels: dir/ab/cd/abcdef...
+func shardedPath(dir string, hash string, shardLevels int) string {
+	parts := []string{dir}
+	for i := 0; i < shardLevels && i*2+2 <= len(hash); i++ {
+		parts = append(parts, hash[i*2:i*2+2])
+	}
+	parts = append(parts, hash)
+	return filepath.Join(parts...)
+}
+
+// save hashes body, writes it once to its content-addressed path if not
+// already present, and appends a manifest.tsv row regardless -- a repeat
+// fetch of the same url, or a different url with an identical body, is
+// recorded without re-writing the payload.
+func (p *payloadStore) save(u url.URL, contentType string, status int, body []byte) error {
+	sum := sha256.Sum256(body)
+	hash := hex.EncodeToString(sum[:])
+
+	path := shardedPath(p.outDir, hash, p.shardLevels)
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		if err := os.MkdirAll(filepath.Dir(path), 0777); err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(path, body, 0666); err != nil {
+			return err
+		}
+	}
+
+	p.Lock()
+	defer p.Unlock()
+
+	line := fmt.Sprintf("%s\t%s\t%s\t%d\t%s\n",
+		u.String(), hash, contentType, status, time.Now().UTC().Format(time.RFC3339))
+	_, err := p.manifest.WriteString(line)
+	return err
+}
+
+func (p *payloadStore) Close() error {
+	return p.manifest.Close()
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestShardedPath(t *testing.T) {
+	hash := "abcdef0123456789"
+
+	cases := []struct {
+		levels int
+		want   string
+	}{
+		{0, filepath.Join("dir", hash)},
+		{1, filepath.Join("dir", "ab", hash)},
+		{2, filepath.Join("dir", "ab", "cd", hash)},
+		{8, filepath.Join("dir", "ab", "cd", "ef", "01", "23", "45", "67", "89", hash)},
+	}
+	for _, c := range cases {
+		if got := shardedPath("dir", hash, c.levels); got != c.want {
+			t.Errorf("shardedPath(levels=%d) = %s, want %s", c.levels, got, c.want)
+		}
+	}
+}
+
+func TestPayloadStoreSaveDedupesIdenticalBodies(t *testing.T) {
+	store, err := newPayloadStore(t.TempDir(), 2)
+	if err != nil {
+		t.Fatalf("newPayloadStore: %s", err)
+	}
+	defer store.Close()
+
+	u1 := mustParseURL(t, "https://example.com/a")
+	u2 := mustParseURL(t, "https://example.com/b")
+	body := []byte("same body")
+
+	if err := store.save(u1, "text/html", 200, body); err != nil {
+		t.Fatalf("save u1: %s", err)
+	}
+	if err := store.save(u2, "text/html", 200, body); err != nil {
+		t.Fatalf("save u2: %s", err)
+	}
+
+	manifestPath := filepath.Join(store.outDir, "manifest.tsv")
+	contents, err := os.ReadFile(manifestPath)
+	if err != nil {
+		t.Fatalf("ReadFile manifest: %s", err)
+	}
+
+	lines := strings.Split(strings.TrimRight(string(contents), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("manifest has %d lines, want 2 (one per url): %q", len(lines), contents)
+	}
+
+	hashes := map[string]bool{}
+	for _, line := range lines {
+		fields := strings.Split(line, "\t")
+		if len(fields) < 2 {
+			t.Fatalf("malformed manifest line: %q", line)
+		}
+		hashes[fields[1]] = true
+	}
+	if len(hashes) != 1 {
+		t.Fatalf("expected both urls to share one content hash, got %v", hashes)
+	}
+
+	var onDiskPayloads int
+	for hash := range hashes {
+		if _, err := os.Stat(shardedPath(store.outDir, hash, 2)); err != nil {
+			t.Fatalf("expected payload at shardedPath for hash=%s: %s", hash, err)
+		}
+		onDiskPayloads++
+	}
+	if onDiskPayloads != 1 {
+		t.Fatalf("expected the identical body to be written once, got %d payload files", onDiskPayloads)
+	}
+}
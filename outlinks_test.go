@@ -0,0 +1,68 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/dibusan/selective-site-crawler/analysis"
+)
+
+// TestExtractOutlinksPreservesType guards the content-type-gate bug: a
+// discovered link's analysis.LinkType must survive resolution so scrape can
+// later decide whether it's exempt from the -content-types preflight.
+func TestExtractOutlinksPreservesType(t *testing.T) {
+	prevIncludeAssets := includeAssets
+	includeAssets = true
+	defer func() { includeAssets = prevIncludeAssets }()
+
+	body := []byte(`<html><body><a href="/page">page</a><img src="/logo.png"></body></html>`)
+	links := extractOutlinks(body, "text/html", "example.com", "example.com")
+
+	var sawHref, sawAsset bool
+	for _, l := range links {
+		switch l.URL.Path {
+		case "/page":
+			sawHref = l.Type.String() == "href"
+		case "/logo.png":
+			sawAsset = l.Type.String() == "asset"
+		}
+	}
+	if !sawHref {
+		t.Fatal("expected /page to resolve with LinkTypeHref")
+	}
+	if !sawAsset {
+		t.Fatal("expected /logo.png to resolve with LinkTypeAsset")
+	}
+}
+
+// TestExtractOutlinksScopesHrefsToOriginalDomain is a regression test: a
+// page fetched from a related host (reachable via -include-assets
+// -exclude-related=false) must not have its own <a href> links treated as
+// in-scope just because they resolve against that foreign host. Scope is
+// always the originally configured -host, not whichever host is currently
+// being scraped.
+func TestExtractOutlinksScopesHrefsToOriginalDomain(t *testing.T) {
+	body := []byte(`<html><body><a href="/totally-unrelated-page">x</a></body></html>`)
+	links := extractOutlinks(body, "text/html", "cdn.example.net", "example.com")
+
+	for _, l := range links {
+		if l.Type == analysis.LinkTypeHref {
+			t.Fatalf("href on foreign pageHost=cdn.example.net was accepted as in-scope: %v", l)
+		}
+	}
+}
+
+func TestSanitizeUrlRelativeRootIsAccepted(t *testing.T) {
+	u, ok := sanitizeUrl("/", "example.com", true, "example.com")
+	if !ok {
+		t.Fatal("sanitizeUrl(\"/\") = false, want true for a relative root href")
+	}
+	if u.Host != "example.com" || u.Path != "/" {
+		t.Fatalf("sanitizeUrl(\"/\") = %v, want host=example.com path=/", u)
+	}
+}
+
+func TestSanitizeUrlAbsoluteRootIsRejected(t *testing.T) {
+	if _, ok := sanitizeUrl("https://example.com/", "example.com", true, "example.com"); ok {
+		t.Fatal("sanitizeUrl(\"https://example.com/\") = true, want false for an absolute root href")
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// runReplay implements the `replay` subcommand: it reads a manifest.tsv
+// produced by payloadStore and reconstructs the classic
+// rootDir/host/path/index.html mirror layout from the content-addressed
+// payload store, for tools that still expect that layout.
+func runReplay(args []string) {
+	fs := flag.NewFlagSet("replay", flag.ExitOnError)
+	payloadDir := fs.String("payloads", "./state/payloads", "Directory holding manifest.tsv and the content-addressed payloads.")
+	outDir := fs.String("out", "/tmp/scraper", "Directory to reconstruct the mirror into.")
+	levels := fs.Int("shard-levels", 2, "Number of sharding directory levels used when the payloads were written.")
+	fs.Parse(args)
+
+	manifestPath := filepath.Join(*payloadDir, "manifest.tsv")
+	f, err := os.Open(manifestPath)
+	if err != nil {
+		logError("replay: cannot open manifest=" + manifestPath + ". err=" + err.Error())
+		os.Exit(1)
+	}
+	defer f.Close()
+
+	count := 0
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Split(scanner.Text(), "\t")
+		if len(fields) < 2 {
+			continue
+		}
+		rawURL, hash := fields[0], fields[1]
+
+		u, err := url.Parse(rawURL)
+		if err != nil {
+			logWarning("replay: skipping unparsable url=" + rawURL)
+			continue
+		}
+
+		body, err := ioutil.ReadFile(shardedPath(*payloadDir, hash, *levels))
+		if err != nil {
+			logWarning("replay: missing payload for url=" + rawURL + " hash=" + hash)
+			continue
+		}
+
+		dirPath := filepath.Join(*outDir, u.Host, u.Path)
+		if err := os.MkdirAll(dirPath, 0777); err != nil {
+			logWarning("replay: cannot create dir=" + dirPath + ". err=" + err.Error())
+			continue
+		}
+
+		dst := filepath.Join(dirPath, "index.html")
+		if err := ioutil.WriteFile(dst, body, 0666); err != nil {
+			logWarning("replay: cannot write file=" + dst + ". err=" + err.Error())
+			continue
+		}
+		count++
+	}
+
+	logInfo(fmt.Sprintf("replay: reconstructed %d pages into %s", count, *outDir))
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+// TestRunReplayMissingManifestDoesNotPanic checks that, with the logger
+// initialised first (as main() does before dispatching to runReplay), a
+// missing manifest makes runReplay exit 1 with a clean message instead of
+// panicking. It's driven via a subprocess since runReplay calls os.Exit on
+// error.
+func TestRunReplayMissingManifestDoesNotPanic(t *testing.T) {
+	if os.Getenv("REPLAY_TEST_HELPER") == "1" {
+		initLogger(VERBOSE)
+		runReplay([]string{"-payloads", "/does/not/exist"})
+		return
+	}
+
+	cmd := exec.Command(os.Args[0], "-test.run=TestRunReplayMissingManifestDoesNotPanic")
+	cmd.Env = append(os.Environ(), "REPLAY_TEST_HELPER=1")
+	out, err := cmd.CombinedOutput()
+
+	exitErr, ok := err.(*exec.ExitError)
+	if !ok {
+		t.Fatalf("expected runReplay to os.Exit(1), got err=%v output=%s", err, out)
+	}
+	if exitErr.ExitCode() != 1 {
+		t.Fatalf("exit code = %d, want 1. output=%s", exitErr.ExitCode(), out)
+	}
+	if strings.Contains(string(out), "panic:") {
+		t.Fatalf("runReplay panicked instead of erroring cleanly. output=%s", out)
+	}
+	if !strings.Contains(string(out), "cannot open manifest") {
+		t.Fatalf("expected a clean manifest error message, got: %s", out)
+	}
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/temoto/robotstxt"
+	"golang.org/x/time/rate"
+)
+
+// userAgent identifies the crawler both on the wire (the User-Agent header,
+// wired up in fetch.go) and when matching robots.txt groups. Set from
+// -user-agent in main().
+var userAgent = "selective-site-crawler"
+
+// crawlDelay is the polite minimum delay between requests to a single host,
+// from -delay. robots.txt's own Crawl-delay overrides it when longer.
+var crawlDelay time.Duration
+
+// globalLimiter enforces -throttle across every host in addition to each
+// host's own per-host limiter.
+var globalLimiter = rate.NewLimiter(rate.Inf, 1)
+
+func initGlobalLimiter(requestsPerSecond float64) {
+	if requestsPerSecond <= 0 {
+		globalLimiter = rate.NewLimiter(rate.Inf, 1)
+		return
+	}
+	globalLimiter = rate.NewLimiter(rate.Limit(requestsPerSecond), 1)
+}
+
+// hostState bundles the robots.txt rules and rate limiter shared by every
+// goroutine crawling a given host, so rate limiting and robots compliance
+// hold across the whole worker pool rather than per-goroutine.
+type hostState struct {
+	robots  *robotstxt.RobotsData
+	limiter *rate.Limiter
+
+	sitemapOnce sync.Once
+	sitemaps    []string
+}
+
+// fetchRobots downloads and parses host's robots.txt. A missing or
+// unparsable robots.txt is treated as "allow everything", per the de facto
+// standard.
+func fetchRobots(host string) *robotstxt.RobotsData {
+	u := url.URL{Scheme: "https", Host: host, Path: "/robots.txt"}
+
+	req, err := http.NewRequest(http.MethodGet, u.String(), nil)
+	if err != nil {
+		logWarning("could not build robots.txt request for host=" + host + ". err=" + err.Error())
+		return nil
+	}
+	req.Header.Set("User-Agent", userAgent)
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		logWarning("could not fetch robots.txt for host=" + host + ". err=" + err.Error())
+		return nil
+	}
+	defer resp.Body.Close()
+
+	robots, err := robotstxt.FromResponse(resp)
+	if err != nil {
+		logWarning("could not parse robots.txt for host=" + host + ". err=" + err.Error())
+		return nil
+	}
+	return robots
+}
+
+// effectiveDelay returns whichever is longer: configured (-delay), or
+// robots' Crawl-delay for userAgent. Split out of newHostState so the
+// precedence rule can be unit tested without a real robots.txt fetch.
+func effectiveDelay(robots *robotstxt.RobotsData, configured time.Duration) time.Duration {
+	delay := configured
+	if robots != nil {
+		if group := robots.FindGroup(userAgent); group != nil && group.CrawlDelay > delay {
+			delay = group.CrawlDelay
+		}
+	}
+	return delay
+}
+
+// newHostState builds the shared robots/rate-limiter state for host. The
+// polite delay is whichever is longer: -delay, or robots.txt's Crawl-delay
+// for userAgent.
+func newHostState(host string) *hostState {
+	robots := fetchRobots(host)
+	delay := effectiveDelay(robots, crawlDelay)
+
+	limiter := rate.NewLimiter(rate.Inf, 1)
+	if delay > 0 {
+		limiter = rate.NewLimiter(rate.Every(delay), 1)
+	}
+
+	sitemaps := []string{(&url.URL{Scheme: "https", Host: host, Path: "/sitemap.xml"}).String()}
+	if robots != nil {
+		sitemaps = append(sitemaps, robots.Sitemaps...)
+	}
+
+	return &hostState{robots: robots, limiter: limiter, sitemaps: sitemaps}
+}
+
+// sitemapSeeds returns the host's sitemap.xml/robots.txt-declared sitemap
+// URLs exactly once, so the crawler seeds them into the frontier the first
+// time a host is visited instead of re-enqueueing them on every page.
+func (h *hostState) sitemapSeeds() []string {
+	var seeds []string
+	h.sitemapOnce.Do(func() {
+		seeds = h.sitemaps
+	})
+	return seeds
+}
+
+// allowed reports whether path may be fetched by userAgent per robots.txt.
+func (h *hostState) allowed(path string) bool {
+	if h.robots == nil {
+		return true
+	}
+	return h.robots.TestAgent(path, userAgent)
+}
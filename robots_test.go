@@ -0,0 +1,77 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/temoto/robotstxt"
+)
+
+func TestEffectiveDelayRobotsCrawlDelayWins(t *testing.T) {
+	robots, err := robotstxt.FromString("User-agent: *\nCrawl-delay: 5\n")
+	if err != nil {
+		t.Fatalf("robotstxt.FromString: %s", err)
+	}
+
+	got := effectiveDelay(robots, time.Second)
+	want := 5 * time.Second
+	if got != want {
+		t.Fatalf("effectiveDelay = %s, want %s (robots Crawl-delay should win over a shorter -delay)", got, want)
+	}
+}
+
+func TestEffectiveDelayConfiguredWinsWhenLonger(t *testing.T) {
+	robots, err := robotstxt.FromString("User-agent: *\nCrawl-delay: 1\n")
+	if err != nil {
+		t.Fatalf("robotstxt.FromString: %s", err)
+	}
+
+	got := effectiveDelay(robots, 5*time.Second)
+	want := 5 * time.Second
+	if got != want {
+		t.Fatalf("effectiveDelay = %s, want %s (-delay should win when longer than robots Crawl-delay)", got, want)
+	}
+}
+
+func TestEffectiveDelayNilRobotsUsesConfigured(t *testing.T) {
+	got := effectiveDelay(nil, 2*time.Second)
+	if got != 2*time.Second {
+		t.Fatalf("effectiveDelay(nil, 2s) = %s, want 2s", got)
+	}
+}
+
+func TestHostStateAllowedRespectsDisallow(t *testing.T) {
+	robots, err := robotstxt.FromString("User-agent: *\nDisallow: /private/\n")
+	if err != nil {
+		t.Fatalf("robotstxt.FromString: %s", err)
+	}
+	hs := &hostState{robots: robots}
+
+	if hs.allowed("/private/secret") {
+		t.Fatal("allowed(/private/secret) = true, want false per robots.txt Disallow")
+	}
+	if !hs.allowed("/public/page") {
+		t.Fatal("allowed(/public/page) = false, want true")
+	}
+}
+
+func TestHostStateAllowedNilRobotsAllowsEverything(t *testing.T) {
+	hs := &hostState{robots: nil}
+	if !hs.allowed("/anything") {
+		t.Fatal("allowed with nil robots = false, want true (missing/unparsable robots.txt allows everything)")
+	}
+}
+
+func TestHostStateSitemapSeedsOnlyOnce(t *testing.T) {
+	hs := &hostState{sitemaps: []string{"https://example.com/sitemap.xml"}}
+
+	first := hs.sitemapSeeds()
+	if len(first) != 1 {
+		t.Fatalf("first sitemapSeeds() = %v, want 1 seed", first)
+	}
+
+	second := hs.sitemapSeeds()
+	if len(second) != 0 {
+		t.Fatalf("second sitemapSeeds() = %v, want no seeds (already handed out once)", second)
+	}
+}
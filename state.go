@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"time"
+
+	"github.com/boltdb/bolt"
+)
+
+var urlsBucket = []byte("urls")
+
+// crawlState persists the set of URLs seen by the crawler to a BoltDB file
+// under dir, so a crawl can be resumed after being interrupted. A URL is
+// stored as soon as it is enqueued (value "0") and updated to "1" once it
+// has actually been fetched.
+type crawlState struct {
+	db *bolt.DB
+}
+
+func openCrawlState(dir string) (*crawlState, error) {
+	path := filepath.Join(dir, "state.db")
+
+	db, err := bolt.Open(path, 0666, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("cannot open state db %s: %s", path, err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(urlsBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &crawlState{db: db}, nil
+}
+
+// markSeen records that u has been enqueued. Returns false if u was already
+// known, in which case the caller should not re-enqueue it.
+func (s *crawlState) markSeen(u url.URL) (bool, error) {
+	isNew := true
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(urlsBucket)
+		key := []byte(u.String())
+		if v := b.Get(key); v != nil {
+			isNew = false
+			return nil
+		}
+		return b.Put(key, []byte("0"))
+	})
+	return isNew, err
+}
+
+// markFetched records that u has been successfully downloaded.
+func (s *crawlState) markFetched(u url.URL) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(urlsBucket).Put([]byte(u.String()), []byte("1"))
+	})
+}
+
+// pending returns every URL that was seen but never marked as fetched, i.e.
+// work that was interrupted mid-crawl and should be re-enqueued on resume.
+func (s *crawlState) pending() ([]url.URL, error) {
+	var pending []url.URL
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(urlsBucket)
+		return b.ForEach(func(k, v []byte) error {
+			if string(v) == "0" {
+				u, err := url.Parse(string(k))
+				if err != nil {
+					logWarning("resume: skipping unparsable stored url " + string(k))
+					return nil
+				}
+				pending = append(pending, *u)
+			}
+			return nil
+		})
+	})
+	return pending, err
+}
+
+// fetched returns every URL already marked as fetched ("1") in the state
+// db. On -resume this seeds concurrentStorage's in-memory dedupe set so
+// urls already downloaded in a prior run aren't treated as new when
+// rediscovered (e.g. via a shared nav link) and re-fetched. Pending ("0")
+// urls are deliberately excluded: those are re-enqueued as seeds by
+// pending() and still need to pass through the normal add() path once.
+func (s *crawlState) fetched() ([]url.URL, error) {
+	var fetched []url.URL
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(urlsBucket)
+		return b.ForEach(func(k, v []byte) error {
+			if string(v) != "1" {
+				return nil
+			}
+			u, err := url.Parse(string(k))
+			if err != nil {
+				logWarning("resume: skipping unparsable stored url " + string(k))
+				return nil
+			}
+			fetched = append(fetched, *u)
+			return nil
+		})
+	})
+	return fetched, err
+}
+
+func (s *crawlState) Close() error {
+	return s.db.Close()
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"net/url"
+	"sort"
+	"testing"
+)
+
+func mustParseURL(t *testing.T, raw string) url.URL {
+	t.Helper()
+	u, err := url.Parse(raw)
+	if err != nil {
+		t.Fatalf("could not parse url=%s: %s", raw, err)
+	}
+	return *u
+}
+
+func TestCrawlStateFetchedExcludesPending(t *testing.T) {
+	state, err := openCrawlState(t.TempDir())
+	if err != nil {
+		t.Fatalf("openCrawlState: %s", err)
+	}
+	defer state.Close()
+
+	seed := mustParseURL(t, "https://example.com/")
+	done := mustParseURL(t, "https://example.com/done")
+
+	for _, u := range []url.URL{seed, done} {
+		if _, err := state.markSeen(u); err != nil {
+			t.Fatalf("markSeen(%s): %s", u.String(), err)
+		}
+	}
+	if err := state.markFetched(done); err != nil {
+		t.Fatalf("markFetched: %s", err)
+	}
+
+	pending, err := state.pending()
+	if err != nil {
+		t.Fatalf("pending: %s", err)
+	}
+	if len(pending) != 1 || pending[0].String() != seed.String() {
+		t.Fatalf("pending = %v, want only %s", pending, seed.String())
+	}
+
+	fetched, err := state.fetched()
+	if err != nil {
+		t.Fatalf("fetched: %s", err)
+	}
+	if len(fetched) != 1 || fetched[0].String() != done.String() {
+		t.Fatalf("fetched = %v, want only %s", fetched, done.String())
+	}
+}
+
+// TestResumePreloadSkipsRefetch is the round-trip test the review asked
+// for: a url already marked fetched in a prior run must come back out of
+// concurrentStorage.add as already-seen once preloaded, so a resumed crawl
+// that rediscovers it (e.g. via a shared nav link) does not re-fetch it.
+func TestResumePreloadSkipsRefetch(t *testing.T) {
+	state, err := openCrawlState(t.TempDir())
+	if err != nil {
+		t.Fatalf("openCrawlState: %s", err)
+	}
+	defer state.Close()
+
+	done := mustParseURL(t, "https://example.com/done")
+	if _, err := state.markSeen(done); err != nil {
+		t.Fatalf("markSeen: %s", err)
+	}
+	if err := state.markFetched(done); err != nil {
+		t.Fatalf("markFetched: %s", err)
+	}
+
+	fetched, err := state.fetched()
+	if err != nil {
+		t.Fatalf("fetched: %s", err)
+	}
+
+	urlSet := newConcurrentStorage("example.com", nil)
+	urlSet.preload(fetched)
+
+	if urlSet.add(done) {
+		t.Fatalf("add(%s) = true after preload, want false (already fetched)", done.String())
+	}
+
+	fresh := mustParseURL(t, "https://example.com/new")
+	if !urlSet.add(fresh) {
+		t.Fatalf("add(%s) = false, want true (never seen)", fresh.String())
+	}
+}
+
+func TestCrawlStateFetchedReturnsEveryFetchedURL(t *testing.T) {
+	state, err := openCrawlState(t.TempDir())
+	if err != nil {
+		t.Fatalf("openCrawlState: %s", err)
+	}
+	defer state.Close()
+
+	urls := []string{"https://example.com/a", "https://example.com/b", "https://example.com/c"}
+	for _, raw := range urls {
+		u := mustParseURL(t, raw)
+		if _, err := state.markSeen(u); err != nil {
+			t.Fatalf("markSeen: %s", err)
+		}
+		if err := state.markFetched(u); err != nil {
+			t.Fatalf("markFetched: %s", err)
+		}
+	}
+
+	fetched, err := state.fetched()
+	if err != nil {
+		t.Fatalf("fetched: %s", err)
+	}
+	var got []string
+	for _, u := range fetched {
+		got = append(got, u.String())
+	}
+	sort.Strings(got)
+	sort.Strings(urls)
+	if len(got) != len(urls) {
+		t.Fatalf("fetched = %v, want %v", got, urls)
+	}
+	for i := range urls {
+		if got[i] != urls[i] {
+			t.Fatalf("fetched = %v, want %v", got, urls)
+		}
+	}
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// warcFilePattern matches the rotating output files this writer produces,
+// e.g. crawl-00001.warc, so an existing -state/warc dir can be resumed
+// without clobbering what a prior run already wrote there.
+const warcFilePattern = "crawl-*.warc"
+
+// warcWriter appends HTTP responses as WARC/1.0 records to a rotating set of
+// .warc files under outDir. A new file is started once the current one would
+// exceed maxSizeBytes, so a long crawl doesn't produce one unbounded file.
+type warcWriter struct {
+	sync.Mutex
+	outDir       string
+	maxSizeBytes int64
+
+	file      *os.File
+	written   int64
+	seq       int
+}
+
+func newWarcWriter(outDir string, maxSizeMB int) (*warcWriter, error) {
+	if err := os.MkdirAll(outDir, 0777); err != nil {
+		return nil, fmt.Errorf("cannot create warc output dir %s: %s", outDir, err)
+	}
+
+	w := &warcWriter{
+		outDir:       outDir,
+		maxSizeBytes: int64(maxSizeMB) * 1024 * 1024,
+	}
+
+	maxSeq, err := existingMaxSeq(outDir)
+	if err != nil {
+		return nil, err
+	}
+
+	if maxSeq == 0 {
+		if err := w.rotate(); err != nil {
+			return nil, err
+		}
+	} else if err := w.resume(maxSeq); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// existingMaxSeq scans outDir for crawl-NNNNN.warc files left by a prior run
+// and returns the highest NNNNN found, or 0 if outDir has none.
+func existingMaxSeq(outDir string) (int, error) {
+	matches, err := filepath.Glob(filepath.Join(outDir, warcFilePattern))
+	if err != nil {
+		return 0, err
+	}
+
+	max := 0
+	for _, m := range matches {
+		var seq int
+		if _, err := fmt.Sscanf(filepath.Base(m), "crawl-%05d.warc", &seq); err != nil {
+			continue
+		}
+		if seq > max {
+			max = seq
+		}
+	}
+	return max, nil
+}
+
+// resume reopens the highest-numbered WARC file from a prior run in append
+// mode, so -resume continues writing into it instead of rotate()'s usual
+// O_TRUNC wiping out the pages it already captured. Later rotations pick up
+// numbering at seq+1.
+func (w *warcWriter) resume(seq int) error {
+	name := fmt.Sprintf("crawl-%05d.warc", seq)
+	path := filepath.Join(w.outDir, name)
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0666)
+	if err != nil {
+		return fmt.Errorf("cannot reopen warc file %s for append: %s", path, err)
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("cannot stat warc file %s: %s", path, err)
+	}
+
+	w.seq = seq
+	w.file = f
+	w.written = info.Size()
+	return nil
+}
+
+// rotate closes the current WARC file (if any) and opens a new one, writing
+// the leading warcinfo record.
+func (w *warcWriter) rotate() error {
+	if w.file != nil {
+		w.file.Close()
+	}
+
+	w.seq++
+	name := fmt.Sprintf("crawl-%05d.warc", w.seq)
+	path := filepath.Join(w.outDir, name)
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0666)
+	if err != nil {
+		return fmt.Errorf("cannot open warc file %s: %s", path, err)
+	}
+
+	w.file = f
+	w.written = 0
+
+	info := warcInfoRecord()
+	n, err := w.file.Write(info)
+	if err != nil {
+		return err
+	}
+	w.written += int64(n)
+	return nil
+}
+
+func warcInfoRecord() []byte {
+	recordID, _ := randomHex(16)
+	body := "software: selective-site-crawler\r\nformat: WARC File Format 1.0\r\n"
+
+	header := fmt.Sprintf("WARC/1.0\r\n"+
+		"WARC-Type: warcinfo\r\n"+
+		"WARC-Record-ID: <urn:uuid:%s>\r\n"+
+		"WARC-Date: %s\r\n"+
+		"Content-Type: application/warc-fields\r\n"+
+		"Content-Length: %d\r\n\r\n%s\r\n\r\n",
+		recordID, time.Now().UTC().Format(time.RFC3339), len(body), body)
+
+	return []byte(header)
+}
+
+// writeResponse appends a single WARC "response" record capturing the raw
+// HTTP response for u. statusLine and headerBlock are the HTTP status line
+// and header block to record; scrape rebuilds them from the parsed
+// http.Response, so they are not the server's bytes verbatim.
+func (w *warcWriter) writeResponse(u url.URL, statusLine string, headerBlock string, body []byte) error {
+	w.Lock()
+	defer w.Unlock()
+
+	httpRecord := statusLine + "\r\n" + headerBlock + "\r\n" + string(body)
+
+	recordID, _ := randomHex(16)
+	header := fmt.Sprintf("WARC/1.0\r\n"+
+		"WARC-Type: response\r\n"+
+		"WARC-Record-ID: <urn:uuid:%s>\r\n"+
+		"WARC-Target-URI: %s\r\n"+
+		"WARC-Date: %s\r\n"+
+		"Content-Type: application/http; msgtype=response\r\n"+
+		"Content-Length: %d\r\n\r\n",
+		recordID, u.String(), time.Now().UTC().Format(time.RFC3339), len(httpRecord))
+
+	record := append([]byte(header), []byte(httpRecord)...)
+	record = append(record, []byte("\r\n\r\n")...)
+
+	if w.written+int64(len(record)) > w.maxSizeBytes {
+		if err := w.rotate(); err != nil {
+			return err
+		}
+	}
+
+	n, err := w.file.Write(record)
+	if err != nil {
+		return err
+	}
+	w.written += int64(n)
+	return nil
+}
+
+func (w *warcWriter) Close() error {
+	w.Lock()
+	defer w.Unlock()
+	if w.file != nil {
+		return w.file.Close()
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestNewWarcWriterResumesWithoutTruncating is a regression test: reopening
+// an existing -state/warc dir (exactly what -resume does) used to truncate
+// the first segment back to just a warcinfo header, discarding every page
+// a prior run had already captured.
+func TestNewWarcWriterResumesWithoutTruncating(t *testing.T) {
+	dir := t.TempDir()
+
+	w, err := newWarcWriter(dir, 100)
+	if err != nil {
+		t.Fatalf("newWarcWriter: %s", err)
+	}
+	u := mustParseURL(t, "https://example.com/a")
+	if err := w.writeResponse(u, "HTTP/1.1 200 OK", "Content-Type: text/html\r\n", []byte("<html>hello</html>")); err != nil {
+		t.Fatalf("writeResponse: %s", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %s", err)
+	}
+
+	path := filepath.Join(dir, "crawl-00001.warc")
+	before, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Stat: %s", err)
+	}
+	if before.Size() == 0 {
+		t.Fatal("expected the first segment to contain data before resuming")
+	}
+
+	w2, err := newWarcWriter(dir, 100)
+	if err != nil {
+		t.Fatalf("newWarcWriter (resume): %s", err)
+	}
+	defer w2.Close()
+
+	after, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Stat after resume: %s", err)
+	}
+	if after.Size() < before.Size() {
+		t.Fatalf("crawl-00001.warc shrank from %d to %d bytes on resume: truncated", before.Size(), after.Size())
+	}
+
+	u2 := mustParseURL(t, "https://example.com/b")
+	if err := w2.writeResponse(u2, "HTTP/1.1 200 OK", "Content-Type: text/html\r\n", []byte("<html>world</html>")); err != nil {
+		t.Fatalf("writeResponse after resume: %s", err)
+	}
+
+	grown, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Stat after second write: %s", err)
+	}
+	if grown.Size() <= after.Size() {
+		t.Fatalf("expected crawl-00001.warc to grow after appending a second record, before=%d after=%d", after.Size(), grown.Size())
+	}
+}
+
+func TestWarcWriterRotatesToNextSeqAfterResume(t *testing.T) {
+	dir := t.TempDir()
+
+	w, err := newWarcWriter(dir, 100)
+	if err != nil {
+		t.Fatalf("newWarcWriter: %s", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %s", err)
+	}
+
+	w2, err := newWarcWriter(dir, 100)
+	if err != nil {
+		t.Fatalf("newWarcWriter (resume): %s", err)
+	}
+	defer w2.Close()
+
+	if err := w2.rotate(); err != nil {
+		t.Fatalf("rotate: %s", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "crawl-00002.warc")); err != nil {
+		t.Fatalf("expected rotate() after resume to start crawl-00002.warc: %s", err)
+	}
+}